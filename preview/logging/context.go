@@ -0,0 +1,92 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"go.opencensus.io/trace"
+	"golang.org/x/net/context"
+	logpb "google.golang.org/genproto/googleapis/logging/v2"
+)
+
+// EntryFromContext returns an Entry with Trace, SpanID, and TraceSampled
+// populated from the span in ctx, if one is present, with Trace resolved to
+// the resource name Stackdriver groups log entries by
+// ("projects/{ProjectID}/traces/{traceID}"). Callers typically use the
+// result as a starting point, setting Payload and Severity themselves:
+//
+//	e := c.EntryFromContext(ctx)
+//	e.Payload = "handled request"
+//	e.Severity = logging.Info
+//	lg.Log(ctx, e)
+func (c *Client) EntryFromContext(ctx context.Context) Entry {
+	var e Entry
+	span := trace.FromContext(ctx)
+	if span == nil {
+		return e
+	}
+	sc := span.SpanContext()
+	e.Trace = fmt.Sprintf("projects/%s/traces/%s", c.projectID, hex.EncodeToString(sc.TraceID[:]))
+	e.SpanID = hex.EncodeToString(sc.SpanID[:])
+	e.TraceSampled = sc.IsSampled()
+	return e
+}
+
+// CallerSourceLocation returns the source location of its caller, skip
+// frames up the stack. skip == 0 identifies the caller of
+// CallerSourceLocation itself. It returns nil if the location could not be
+// determined.
+func CallerSourceLocation(skip int) *logpb.LogEntrySourceLocation {
+	pc, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return nil
+	}
+	loc := &logpb.LogEntrySourceLocation{File: file, Line: int64(line)}
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		loc.Function = fn.Name()
+	}
+	return loc
+}
+
+// callerSourceLocation walks the stack starting at its own caller, skipping
+// frames that belong to the standard log package (Print, Printf, Output,
+// ...) and to this package's severityWriter, to find the frame where the
+// user actually called into a StandardLogger.
+func callerSourceLocation() *logpb.LogEntrySourceLocation {
+	var pcs [32]uintptr
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if !isInternalFrame(f.Function) {
+			return &logpb.LogEntrySourceLocation{
+				File:     f.File,
+				Line:     int64(f.Line),
+				Function: f.Function,
+			}
+		}
+		if !more {
+			return nil
+		}
+	}
+}
+
+func isInternalFrame(function string) bool {
+	return strings.HasPrefix(function, "log.") || strings.Contains(function, "preview/logging.(*severityWriter)")
+}
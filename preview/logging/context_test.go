@@ -0,0 +1,118 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"encoding/hex"
+	"runtime"
+	"strings"
+	"testing"
+
+	ltesting "cloud.google.com/go/preview/logging/internal/testing"
+	"go.opencensus.io/trace"
+	"golang.org/x/net/context"
+	"google.golang.org/api/option"
+	logpb "google.golang.org/genproto/googleapis/logging/v2"
+	"google.golang.org/grpc"
+)
+
+func TestEntryFromContext(t *testing.T) {
+	c := &Client{projectID: "PROJECT_ID"}
+	if got := c.EntryFromContext(context.Background()); got.Trace != "" || got.SpanID != "" {
+		t.Errorf("no span in context: got %+v, want empty Trace/SpanID", got)
+	}
+
+	sc := trace.SpanContext{
+		TraceID:      trace.TraceID{1, 2, 3, 4},
+		SpanID:       trace.SpanID{5, 6, 7, 8},
+		TraceOptions: trace.TraceOptions(1), // sampled
+	}
+	ctx, span := trace.StartSpanWithRemoteParent(context.Background(), "test", sc)
+	defer span.End()
+	got := c.EntryFromContext(ctx)
+	want := "projects/PROJECT_ID/traces/" + hex.EncodeToString(sc.TraceID[:])
+	if got.Trace != want {
+		t.Errorf("Trace: got %q, want %q", got.Trace, want)
+	}
+	if got.SpanID == "" {
+		t.Errorf("span in context: got %+v, want non-empty SpanID", got)
+	}
+	if !got.TraceSampled {
+		t.Error("got TraceSampled = false, want true")
+	}
+}
+
+func callerForTest() int {
+	loc := CallerSourceLocation(0)
+	return int(loc.Line)
+}
+
+// TestStandardLoggerSourceLocation exercises the runtime.Callers walk in
+// callerSourceLocation through StandardLogger's actual write path, to make
+// sure it resolves to this test's own call site rather than to a frame
+// inside the standard log package or severityWriter itself.
+func TestStandardLoggerSourceLocation(t *testing.T) {
+	addr, err := ltesting.NewServer()
+	if err != nil {
+		t.Fatalf("creating fake server: %v", err)
+	}
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("dialing %q: %v", addr, err)
+	}
+	c, err := NewClient(context.Background(), "PROJECT_ID", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("creating client: %v", err)
+	}
+	c.OnError = func(error) {}
+	lg := c.Logger("source-location-test")
+
+	var got *logpb.LogEntrySourceLocation
+	ltesting.SetWriteLogEntriesHook(addr, func(req *logpb.WriteLogEntriesRequest) error {
+		got = req.Entries[0].SourceLocation
+		return nil
+	})
+
+	_, _, here, _ := runtime.Caller(0)
+	wantLine := int64(here + 2)
+	lg.StandardLogger(Info).Print("hello") // the call site wantLine refers to
+	lg.Flush()
+
+	if got == nil {
+		t.Fatal("got nil SourceLocation")
+	}
+	if strings.HasPrefix(got.Function, "log.") || strings.Contains(got.Function, "preview/logging.(*severityWriter)") {
+		t.Errorf("Function = %q, want the test's own call site, not a log or severityWriter frame", got.Function)
+	}
+	if !strings.Contains(got.Function, "TestStandardLoggerSourceLocation") {
+		t.Errorf("Function = %q, want it to mention TestStandardLoggerSourceLocation", got.Function)
+	}
+	if got.Line != wantLine {
+		t.Errorf("Line = %d, want %d (the Print call above)", got.Line, wantLine)
+	}
+}
+
+func TestCallerSourceLocation(t *testing.T) {
+	loc := CallerSourceLocation(0)
+	if loc == nil {
+		t.Fatal("got nil SourceLocation")
+	}
+	if loc.Function == "" {
+		t.Error("got empty Function")
+	}
+	if got := callerForTest(); got == 0 {
+		t.Error("got Line == 0 for a nested caller")
+	}
+}
@@ -0,0 +1,768 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package logging contains a Google Cloud Logging client suitable for writing
+// log entries from a running service. For reading logs, and working with
+// sinks, metrics and logs in general, see package cloud.google.com/go/logadmin.
+//
+// This package is experimental and subject to change.
+package logging // import "cloud.google.com/go/preview/logging"
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/internal/bundler"
+	vkit "cloud.google.com/go/logging/apiv2"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"golang.org/x/net/context"
+	"google.golang.org/api/option"
+	mrpb "google.golang.org/genproto/googleapis/api/monitoredres"
+	logtypepb "google.golang.org/genproto/googleapis/logging/type"
+	logpb "google.golang.org/genproto/googleapis/logging/v2"
+)
+
+const (
+	// AdminScope is the scope for administering the logging service.
+	AdminScope = "https://www.googleapis.com/auth/logging.admin"
+
+	// WriteScope is the scope for writing log entries.
+	WriteScope = "https://www.googleapis.com/auth/logging.write"
+
+	// ReadScope is the scope for reading log entries.
+	ReadScope = "https://www.googleapis.com/auth/logging.read"
+)
+
+// now is a variable so tests can override it.
+var now = time.Now
+
+// Client is a Logging client. A Client is associated with a single Cloud
+// project.
+type Client struct {
+	client    *vkit.Client
+	projectID string
+	errc      chan error    // should be buffered to minimize blocking
+	donec     chan struct{} // closed on Close to terminate error logger
+
+	// OnError is called when an error occurs in a call to Log or Flush. The
+	// error may be due to an invalid Entry, an overflow because
+	// BufferedByteLimit was reached, or an error communicating with the
+	// logging service. OnError is called with a short delay after the error
+	// occurs, because errors are processed in a separate goroutine. If
+	// OnError is not set, errors are logged using log.Println.
+	OnError func(err error)
+}
+
+// NewClient returns a new logging client associated with the provided project ID.
+//
+// By default NewClient uses WriteScope. To use a different scope, call
+// option.WithScopes.
+func NewClient(ctx context.Context, projectID string, opts ...option.ClientOption) (*Client, error) {
+	opts = append([]option.ClientOption{option.WithScopes(WriteScope)}, opts...)
+	c, err := vkit.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	client := &Client{
+		client:    c,
+		projectID: projectID,
+		errc:      make(chan error, defaultErrorCapacity),
+		donec:     make(chan struct{}),
+	}
+	go client.logErrors()
+	return client, nil
+}
+
+const defaultErrorCapacity = 10
+
+// logErrors is intended to be run as a single goroutine. It reads from errc
+// and, if no OnError handler is set, logs the errors with log.Println.
+func (c *Client) logErrors() {
+	for {
+		select {
+		case <-c.donec:
+			return
+		case err := <-c.errc:
+			if c.OnError != nil {
+				c.OnError(err)
+			} else {
+				log.Println("logging client:", err)
+			}
+		}
+	}
+}
+
+func (c *Client) error(err error) {
+	select {
+	case c.errc <- err:
+	default:
+	}
+}
+
+// Ping reports whether the client's connection to the logging service and
+// the authentication configuration are valid.
+func (c *Client) Ping(ctx context.Context) error {
+	req := &logpb.WriteLogEntriesRequest{
+		LogName:  fmt.Sprintf("projects/%s/logs/ping", c.projectID),
+		Resource: &mrpb.MonitoredResource{Type: "global"},
+		Entries:  []*logpb.LogEntry{},
+	}
+	_, err := c.client.WriteLogEntries(ctx, req)
+	return err
+}
+
+// DeleteLog deletes a log and all its log entries. The log will reappear if
+// it receives new log entries.
+func (c *Client) DeleteLog(ctx context.Context, logID string) error {
+	return c.client.DeleteLog(ctx, &logpb.DeleteLogRequest{
+		LogName: fmt.Sprintf("projects/%s/logs/%s", c.projectID, logID),
+	})
+}
+
+// Close closes the client.
+func (c *Client) Close() error {
+	close(c.donec)
+	return c.client.Close()
+}
+
+// A Logger is used to write log messages to a single log. It can be used
+// from multiple goroutines simultaneously.
+type Logger struct {
+	client        *Client
+	logName       string // e.g. "projects/P/logs/L"
+	resource      *mrpb.MonitoredResource
+	commonLabels  map[string]string
+	bundler       *bundler.Bundler
+	retry         retryConfig
+	overflow      OverflowPolicy
+	overflowQueue overflowQueue
+	stats         loggerStats
+
+	stdMu      sync.Mutex
+	stdLoggers map[Severity]*log.Logger
+}
+
+const (
+	// DefaultDelayThreshold is the default value for the DelayThreshold LoggerOption.
+	DefaultDelayThreshold = 1 * time.Second
+
+	// DefaultEntryCountThreshold is the default value for the EntryCountThreshold LoggerOption.
+	DefaultEntryCountThreshold = 1000
+
+	// DefaultEntryByteThreshold is the default value for the EntryByteThreshold LoggerOption.
+	DefaultEntryByteThreshold = 1 << 20 // 1MiB
+
+	// DefaultBufferedByteLimit is the default value for the BufferedByteLimit LoggerOption.
+	DefaultBufferedByteLimit = 1 << 30 // 1GiB
+)
+
+// LoggerOption is a configuration option for a Logger.
+type LoggerOption interface {
+	set(*Logger)
+}
+
+type logOption func(*Logger)
+
+func (o logOption) set(l *Logger) { o(l) }
+
+// CommonLabels are labels that apply to all the log entries written from a Logger.
+func CommonLabels(m map[string]string) LoggerOption {
+	return logOption(func(l *Logger) { l.commonLabels = m })
+}
+
+// Resource sets the monitored resource associated with all log entries
+// written from a Logger. If not provided, a resource of type "global" is
+// used.
+func Resource(r *mrpb.MonitoredResource) LoggerOption {
+	return logOption(func(l *Logger) { l.resource = r })
+}
+
+// DelayThreshold is the maximum amount of time that an entry can be buffered
+// in memory before a batch is sent, regardless of size. It corresponds to
+// bundler.Bundler.DelayThreshold.
+func DelayThreshold(d time.Duration) LoggerOption {
+	return logOption(func(l *Logger) { l.bundler.DelayThreshold = d })
+}
+
+// EntryCountThreshold is the maximum number of entries that will be buffered
+// before a batch is sent. It corresponds to bundler.Bundler.BundleCountThreshold.
+func EntryCountThreshold(n int) LoggerOption {
+	return logOption(func(l *Logger) { l.bundler.BundleCountThreshold = n })
+}
+
+// EntryByteThreshold is the maximum number of bytes of entries that will be
+// buffered before a batch is sent. It corresponds to bundler.Bundler.BundleByteThreshold.
+func EntryByteThreshold(n int) LoggerOption {
+	return logOption(func(l *Logger) { l.bundler.BundleByteThreshold = n })
+}
+
+// EntryByteLimit is the maximum number of bytes of entries in a single
+// bundle. It corresponds to bundler.Bundler.BundleByteLimit.
+func EntryByteLimit(n int) LoggerOption {
+	return logOption(func(l *Logger) { l.bundler.BundleByteLimit = n })
+}
+
+// BufferedByteLimit is the maximum number of bytes that the Logger will keep
+// in memory before it starts dropping entries. It corresponds to
+// bundler.Bundler.BufferedByteLimit.
+func BufferedByteLimit(n int) LoggerOption {
+	return logOption(func(l *Logger) { l.bundler.BufferedByteLimit = n })
+}
+
+// Logger creates a Logger that writes entries to the log with the given ID,
+// which need not be unique to the project. A log ID is a string that, once
+// encoded, is limited to 512 bytes. It can include only letters, digits,
+// underscores, hyphens, and periods.
+func (c *Client) Logger(logID string, opts ...LoggerOption) *Logger {
+	l := &Logger{
+		client:     c,
+		logName:    fmt.Sprintf("projects/%s/logs/%s", c.projectID, logID),
+		resource:   &mrpb.MonitoredResource{Type: "global"},
+		stdLoggers: map[Severity]*log.Logger{},
+		retry:      defaultRetryConfig(),
+		overflow:   DropNewest,
+	}
+	l.bundler = bundler.NewBundler(&logpb.LogEntry{}, func(entries interface{}) {
+		l.writeLogEntries(entries.([]*logpb.LogEntry))
+	})
+	l.bundler.DelayThreshold = DefaultDelayThreshold
+	l.bundler.BundleCountThreshold = DefaultEntryCountThreshold
+	l.bundler.BundleByteThreshold = DefaultEntryByteThreshold
+	l.bundler.BufferedByteLimit = DefaultBufferedByteLimit
+	for _, opt := range opts {
+		opt.set(l)
+	}
+	return l
+}
+
+// Log buffers the Entry for output to the logging service. By default it
+// never blocks: if the buffer is full, the entry is dropped according to
+// the Logger's BufferOverflowPolicy. If that policy is Block, Log instead
+// waits for room to free up, up to ctx's deadline.
+func (l *Logger) Log(ctx context.Context, e Entry) error {
+	ent, err := l.toLogEntry(e)
+	if err != nil {
+		l.client.error(err)
+		return err
+	}
+	if err := l.addToBundler(ctx, ent, proto.Size(ent)); err != nil {
+		l.client.error(err)
+		return err
+	}
+	return nil
+}
+
+// LogSync logs the Entry synchronously without any buffering.
+func (l *Logger) LogSync(ctx context.Context, e Entry) error {
+	ent, err := l.toLogEntry(e)
+	if err != nil {
+		return err
+	}
+	return l.writeLogEntriesWithRetry(ctx, &logpb.WriteLogEntriesRequest{
+		LogName:  l.logName,
+		Resource: l.resource,
+		Labels:   l.commonLabels,
+		Entries:  []*logpb.LogEntry{ent},
+	})
+}
+
+// Flush blocks until all currently buffered log entries are sent, including
+// any entries a DropOldest policy had queued because the bundler was full.
+func (l *Logger) Flush() error {
+	l.bundler.Flush()
+	l.overflowQueue.drain(func(item interface{}, size int) error {
+		err := l.bundler.Add(item, size)
+		if err != nil {
+			// Send what the bundler holds so the next try has room.
+			l.bundler.Flush()
+		}
+		return err
+	}, true)
+	l.bundler.Flush()
+	return nil
+}
+
+// writeLogEntries is the bundler handler: it is called with a batch of
+// entries accumulated by Log.
+func (l *Logger) writeLogEntries(entries []*logpb.LogEntry) {
+	req := &logpb.WriteLogEntriesRequest{
+		LogName:  l.logName,
+		Resource: l.resource,
+		Labels:   l.commonLabels,
+		Entries:  entries,
+	}
+	var size int64
+	for _, e := range entries {
+		size += int64(proto.Size(e))
+	}
+	atomic.AddInt64(&l.stats.bytesInFlight, size)
+	defer atomic.AddInt64(&l.stats.bytesInFlight, -size)
+	// The bundler has already removed these bytes from its buffered-byte
+	// accounting by the time it calls us, so retrying here does not
+	// double-count against BufferedByteLimit.
+	if err := l.writeLogEntriesWithRetry(context.Background(), req); err != nil {
+		l.client.error(err)
+	}
+}
+
+// StandardLogger returns a *log.Logger that writes to l at the given
+// severity. Each call with the same severity returns the same *log.Logger.
+// Entries written through it have SourceLocation populated with the caller's
+// file, line, and function, without the caller needing to plumb that
+// through itself.
+func (l *Logger) StandardLogger(s Severity) *log.Logger {
+	l.stdMu.Lock()
+	defer l.stdMu.Unlock()
+	if sl, ok := l.stdLoggers[s]; ok {
+		return sl
+	}
+	sl := log.New(&severityWriter{l: l, s: s}, "", 0)
+	l.stdLoggers[s] = sl
+	return sl
+}
+
+// severityWriter implements io.Writer by logging to l at the given severity.
+type severityWriter struct {
+	l *Logger
+	s Severity
+}
+
+func (w *severityWriter) Write(p []byte) (n int, err error) {
+	w.l.Log(context.Background(), Entry{
+		Payload:        string(p),
+		Severity:       w.s,
+		SourceLocation: callerSourceLocation(),
+	})
+	return len(p), nil
+}
+
+// Severity is the severity of a log entry.
+type Severity int
+
+const (
+	Default   Severity = Severity(logtypepb.LogSeverity_DEFAULT)
+	Debug     Severity = Severity(logtypepb.LogSeverity_DEBUG)
+	Info      Severity = Severity(logtypepb.LogSeverity_INFO)
+	Notice    Severity = Severity(logtypepb.LogSeverity_NOTICE)
+	Warning   Severity = Severity(logtypepb.LogSeverity_WARNING)
+	Error     Severity = Severity(logtypepb.LogSeverity_ERROR)
+	Critical  Severity = Severity(logtypepb.LogSeverity_CRITICAL)
+	Alert     Severity = Severity(logtypepb.LogSeverity_ALERT)
+	Emergency Severity = Severity(logtypepb.LogSeverity_EMERGENCY)
+)
+
+// String returns the name of the severity, or a numeric string if the
+// severity is not one of the defined values.
+func (v Severity) String() string {
+	name, ok := logtypepb.LogSeverity_name[int32(v)]
+	if !ok {
+		return fmt.Sprintf("%d", int32(v))
+	}
+	if v == Default {
+		return "Default"
+	}
+	return name[0:1] + strLower(name[1:])
+}
+
+func strLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + ('a' - 'A')
+		}
+	}
+	return string(b)
+}
+
+// HTTPRequest contains an http.Request and additional information about its
+// result for inclusion in a log entry.
+type HTTPRequest struct {
+	// Request is the http.Request passed into the handler.
+	Request *http.Request
+
+	// RequestSize is the size of the HTTP request message in bytes,
+	// including the request headers and the request body.
+	RequestSize int64
+
+	// Status is the response code indicating the status of the response.
+	Status int
+
+	// ResponseSize is the size of the HTTP response message in bytes, as
+	// measured by the outgoing byte count.
+	ResponseSize int64
+
+	// RemoteIP is the IP address from which the request originated.
+	RemoteIP string
+
+	// CacheHit indicates whether the request was served from cache.
+	CacheHit bool
+
+	// CacheValidatedWithOriginServer indicates whether the request was
+	// validated with the origin server before being served from cache.
+	CacheValidatedWithOriginServer bool
+}
+
+func fromHTTPRequest(r *HTTPRequest) *logtypepb.HttpRequest {
+	if r == nil {
+		return nil
+	}
+	if r.Request == nil {
+		panic("HTTPRequest must have a non-nil Request")
+	}
+	pb := &logtypepb.HttpRequest{
+		RequestMethod:                  r.Request.Method,
+		RequestSize:                    r.RequestSize,
+		Status:                         int32(r.Status),
+		ResponseSize:                   r.ResponseSize,
+		UserAgent:                      r.Request.UserAgent(),
+		RemoteIp:                       r.RemoteIP,
+		Referer:                        r.Request.Referer(),
+		CacheHit:                       r.CacheHit,
+		CacheValidatedWithOriginServer: r.CacheValidatedWithOriginServer,
+	}
+	if r.Request.URL != nil {
+		pb.RequestUrl = r.Request.URL.String()
+	}
+	return pb
+}
+
+func toHTTPRequest(pb *logtypepb.HttpRequest) (*HTTPRequest, error) {
+	if pb == nil {
+		return nil, nil
+	}
+	u, err := url.Parse(pb.RequestUrl)
+	if err != nil {
+		return nil, err
+	}
+	req := &http.Request{
+		Method: pb.RequestMethod,
+		URL:    u,
+		Header: map[string][]string{},
+	}
+	if pb.UserAgent != "" {
+		req.Header.Set("User-Agent", pb.UserAgent)
+	}
+	if pb.Referer != "" {
+		req.Header.Set("Referer", pb.Referer)
+	}
+	return &HTTPRequest{
+		Request:                        req,
+		RequestSize:                    pb.RequestSize,
+		Status:                         int(pb.Status),
+		ResponseSize:                   pb.ResponseSize,
+		RemoteIP:                       pb.RemoteIp,
+		CacheHit:                       pb.CacheHit,
+		CacheValidatedWithOriginServer: pb.CacheValidatedWithOriginServer,
+	}, nil
+}
+
+// Entry is a log entry.
+type Entry struct {
+	// Timestamp is the time of the entry. If zero, the current time is used.
+	Timestamp time.Time
+
+	// Severity is the entry's severity level.
+	Severity Severity
+
+	// Payload must be either a string, or something that marshals via the
+	// encoding/json package to a JSON object (and not any other type of
+	// JSON value).
+	Payload interface{}
+
+	// Labels optionally specifies key/value labels for the log entry.
+	Labels map[string]string
+
+	// InsertID is a unique ID for the log entry. If you provide this field,
+	// the logging service considers other log entries in the same log with
+	// the same ID as duplicates which can be removed.
+	InsertID string
+
+	// HTTPRequest optionally specifies metadata about the HTTP request
+	// associated with this log entry, if applicable.
+	HTTPRequest *HTTPRequest
+
+	// LogName is the full name of the log, in the format
+	// "projects/{ProjectID}/logs/{LogID}". Set by the client when reading
+	// entries back.
+	LogName string
+
+	// Resource is the monitored resource associated with the entry.
+	Resource *mrpb.MonitoredResource
+
+	// Trace is the resource name of the trace associated with the entry, if
+	// any. Stackdriver's UI groups log entries that share a Trace.
+	Trace string
+
+	// SpanID is the ID of the span within Trace associated with the entry,
+	// if any.
+	SpanID string
+
+	// TraceSampled indicates whether the trace associated with the entry was
+	// sampled.
+	TraceSampled bool
+
+	// SourceLocation identifies the source code location from which the
+	// entry was logged, if known.
+	SourceLocation *logpb.LogEntrySourceLocation
+
+	// Operation identifies a long-running operation that this entry is part
+	// of, if any.
+	Operation *logpb.LogEntryOperation
+}
+
+// toLogEntry converts e to a logpb.LogEntry.
+func (l *Logger) toLogEntry(e Entry) (*logpb.LogEntry, error) {
+	t := e.Timestamp
+	if t.IsZero() {
+		t = now()
+	}
+	ts, err := ptypes.TimestampProto(t)
+	if err != nil {
+		return nil, err
+	}
+	ent := &logpb.LogEntry{
+		Timestamp:      ts,
+		Severity:       logtypepb.LogSeverity(e.Severity),
+		InsertId:       e.InsertID,
+		HttpRequest:    fromHTTPRequest(e.HTTPRequest),
+		Labels:         e.Labels,
+		Trace:          e.Trace,
+		SpanId:         e.SpanID,
+		TraceSampled:   e.TraceSampled,
+		SourceLocation: e.SourceLocation,
+		Operation:      e.Operation,
+	}
+	switch p := e.Payload.(type) {
+	case string:
+		ent.Payload = &logpb.LogEntry_TextPayload{p}
+	default:
+		s, err := toProtoStruct(p)
+		if err != nil {
+			return nil, err
+		}
+		ent.Payload = &logpb.LogEntry_JsonPayload{s}
+	}
+	return ent, nil
+}
+
+// fromLogEntry converts a logpb.LogEntry into an Entry.
+func fromLogEntry(logEntry *logpb.LogEntry) (*Entry, error) {
+	t, err := ptypes.Timestamp(logEntry.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	var payload interface{}
+	switch x := logEntry.Payload.(type) {
+	case *logpb.LogEntry_TextPayload:
+		payload = x.TextPayload
+
+	case *logpb.LogEntry_ProtoPayload:
+		payload = x.ProtoPayload
+
+	case *logpb.LogEntry_JsonPayload:
+		payload, err = jsonValueToInterface(x.JsonPayload)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("logging: unknown payload type: %T", x)
+	}
+	req, err := toHTTPRequest(logEntry.HttpRequest)
+	if err != nil {
+		return nil, err
+	}
+	return &Entry{
+		LogName:        logEntry.LogName,
+		Resource:       logEntry.Resource,
+		Timestamp:      t.In(time.UTC),
+		Severity:       Severity(logEntry.Severity),
+		Payload:        payload,
+		Labels:         logEntry.Labels,
+		InsertID:       logEntry.InsertId,
+		HTTPRequest:    req,
+		Trace:          logEntry.Trace,
+		SpanID:         logEntry.SpanId,
+		TraceSampled:   logEntry.TraceSampled,
+		SourceLocation: logEntry.SourceLocation,
+		Operation:      logEntry.Operation,
+	}, nil
+}
+
+// jsonValueToInterface converts a structpb.Struct, the internal
+// representation of a JSON object, into a Go value suitable for storage in
+// an Entry's Payload field.
+func jsonValueToInterface(s *structpb.Struct) (interface{}, error) {
+	b, err := json.Marshal(structToMap(s))
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func structToMap(s *structpb.Struct) map[string]interface{} {
+	m := map[string]interface{}{}
+	for k, v := range s.Fields {
+		m[k] = valueToInterface(v)
+	}
+	return m
+}
+
+func valueToInterface(v *structpb.Value) interface{} {
+	switch x := v.Kind.(type) {
+	case *structpb.Value_NullValue:
+		return nil
+	case *structpb.Value_NumberValue:
+		return x.NumberValue
+	case *structpb.Value_StringValue:
+		return x.StringValue
+	case *structpb.Value_BoolValue:
+		return x.BoolValue
+	case *structpb.Value_StructValue:
+		return structToMap(x.StructValue)
+	case *structpb.Value_ListValue:
+		var out []interface{}
+		for _, e := range x.ListValue.Values {
+			out = append(out, valueToInterface(e))
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// toProtoStruct converts v, which must marshal into a JSON object, into a
+// structpb.Struct.
+func toProtoStruct(v interface{}) (*structpb.Struct, error) {
+	if s, ok := v.(*structpb.Struct); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("logging: value %v cannot be converted to a structpb.Struct: %v", v, err)
+	}
+	fields := map[string]*structpb.Value{}
+	for k, val := range m {
+		pv, err := interfaceToValue(val)
+		if err != nil {
+			return nil, err
+		}
+		fields[k] = pv
+	}
+	return &structpb.Struct{Fields: fields}, nil
+}
+
+func interfaceToValue(i interface{}) (*structpb.Value, error) {
+	switch x := i.(type) {
+	case nil:
+		return &structpb.Value{Kind: &structpb.Value_NullValue{}}, nil
+	case bool:
+		return &structpb.Value{Kind: &structpb.Value_BoolValue{x}}, nil
+	case float64:
+		return &structpb.Value{Kind: &structpb.Value_NumberValue{x}}, nil
+	case string:
+		return &structpb.Value{Kind: &structpb.Value_StringValue{x}}, nil
+	case map[string]interface{}:
+		s, err := toProtoStruct(x)
+		if err != nil {
+			return nil, err
+		}
+		return &structpb.Value{Kind: &structpb.Value_StructValue{s}}, nil
+	case []interface{}:
+		var vals []*structpb.Value
+		for _, e := range x {
+			v, err := interfaceToValue(e)
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, v)
+		}
+		return &structpb.Value{Kind: &structpb.Value_ListValue{&structpb.ListValue{vals}}}, nil
+	default:
+		return nil, fmt.Errorf("logging: value %v of unknown type (%T) cannot be converted to a structpb.Value", i, i)
+	}
+}
+
+// EntriesOption is an option for listing log entries.
+type EntriesOption interface {
+	set(*logpb.ListLogEntriesRequest)
+}
+
+type entriesOption func(*logpb.ListLogEntriesRequest)
+
+func (o entriesOption) set(r *logpb.ListLogEntriesRequest) { o(r) }
+
+// ProjectIDs sets the project IDs whose logs are to be read.
+func ProjectIDs(pids []string) EntriesOption {
+	return entriesOption(func(r *logpb.ListLogEntriesRequest) { r.ProjectIds = pids })
+}
+
+// Filter specifies the advanced logs filter for listing log entries.
+func Filter(f string) EntriesOption {
+	return entriesOption(func(r *logpb.ListLogEntriesRequest) { r.Filter = f })
+}
+
+// OrderBy specifies the sort order of listed log entries.
+func OrderBy(ob string) EntriesOption {
+	return entriesOption(func(r *logpb.ListLogEntriesRequest) { r.OrderBy = ob })
+}
+
+func listLogEntriesRequest(projectID string, opts []EntriesOption) *logpb.ListLogEntriesRequest {
+	req := &logpb.ListLogEntriesRequest{ProjectIds: []string{projectID}}
+	for _, opt := range opts {
+		opt.set(req)
+	}
+	return req
+}
+
+// EntryIterator iterates over log entries.
+type EntryIterator struct {
+	ctx    context.Context
+	client *Client
+	req    *logpb.ListLogEntriesRequest
+}
+
+// Entries creates an EntryIterator for iterating over log entries. By
+// default, the log entries of the client's project are returned.
+func (c *Client) Entries(ctx context.Context, opts ...EntriesOption) *EntryIterator {
+	return &EntryIterator{
+		ctx:    ctx,
+		client: c,
+		req:    listLogEntriesRequest(c.projectID, opts),
+	}
+}
+
+// Next always returns a nil Entry and a non-nil error: listing entries is
+// not implemented in this package, so Next never yields a result and never
+// returns iterator.Done.
+func (it *EntryIterator) Next() (*Entry, error) {
+	return nil, errors.New("logging: EntryIterator.Next requires a paging client not available in this snapshot")
+}
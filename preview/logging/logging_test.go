@@ -276,7 +276,7 @@ func TestLogAndEntries(t *testing.T) {
 	lg := client.Logger(testLogID)
 	defer deleteLog(ctx, testLogID)
 	for _, p := range payloads {
-		lg.Log(Entry{Payload: p})
+		lg.Log(ctx, Entry{Payload: p})
 	}
 	lg.Flush()
 	var want []*Entry
@@ -413,6 +413,14 @@ func TestFromLogEntry(t *testing.T) {
 			"b": "two",
 			"c": "true",
 		},
+		Trace:        "projects/PROJECT_ID/traces/06796866738c859f2f19b7cfb3214824",
+		SpanId:       "000000000000004a",
+		TraceSampled: true,
+		SourceLocation: &logpb.LogEntrySourceLocation{
+			File:     "file.go",
+			Line:     42,
+			Function: "foo",
+		},
 	}
 	u, err := url.Parse("http:://example.com/path?q=1")
 	if err != nil {
@@ -429,7 +437,15 @@ func TestFromLogEntry(t *testing.T) {
 			"b": "two",
 			"c": "true",
 		},
-		InsertID: "123",
+		InsertID:     "123",
+		Trace:        "projects/PROJECT_ID/traces/06796866738c859f2f19b7cfb3214824",
+		SpanID:       "000000000000004a",
+		TraceSampled: true,
+		SourceLocation: &logpb.LogEntrySourceLocation{
+			File:     "file.go",
+			Line:     42,
+			Function: "foo",
+		},
 		HTTPRequest: &HTTPRequest{
 			Request: &http.Request{
 				Method: "GET",
@@ -519,7 +535,7 @@ loop:
 	}
 	// Try to log something that can't be JSON-marshalled.
 	lg := client.Logger(testLogID)
-	lg.Log(Entry{Payload: func() {}})
+	lg.Log(context.Background(), Entry{Payload: func() {}})
 	// Expect an error.
 	select {
 	case <-errorc: // pass
@@ -0,0 +1,226 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/internal/bundler"
+	"golang.org/x/net/context"
+	logpb "google.golang.org/genproto/googleapis/logging/v2"
+)
+
+// OverflowPolicy controls what a Logger does when Log is called while its
+// buffer is already at BufferedByteLimit.
+type OverflowPolicy int
+
+const (
+	// DropNewest discards the entry passed to Log and reports an error
+	// through the Client's OnError. This is the default, and matches the
+	// Logger's historical behavior.
+	DropNewest OverflowPolicy = iota
+
+	// DropOldest admits the entry passed to Log by evicting the oldest
+	// entries from the Logger's own staging queue (see overflowQueue),
+	// incrementing DroppedEntries for each one evicted. Entries already
+	// added to the bundler are not evicted. Unlike DropNewest, the caller's
+	// own entry always makes it into the queue.
+	DropOldest
+
+	// Block waits for room to free up in the buffer, or for the Context
+	// passed to Log to be done, whichever comes first.
+	Block
+)
+
+// BufferOverflowPolicy sets what happens when Log is called while the
+// Logger's buffer is full. The default is DropNewest.
+func BufferOverflowPolicy(p OverflowPolicy) LoggerOption {
+	return logOption(func(l *Logger) { l.overflow = p })
+}
+
+// blockPollInterval is how often Block mode re-checks the bundler for room
+// while waiting. The bundler has no "room freed up" signal, so we poll.
+const blockPollInterval = 10 * time.Millisecond
+
+// loggerStats holds the counters backing Logger.Stats. All fields are
+// updated with the atomic package so they are safe to read concurrently
+// with in-flight Log calls.
+type loggerStats struct {
+	droppedEntries  int64
+	bytesInFlight   int64
+	retriesInFlight int64
+}
+
+// Stats reports counters useful for monitoring a Logger under load.
+type Stats struct {
+	// DroppedEntries is the number of entries discarded because the buffer
+	// was full: under DropNewest, the entry passed to Log; under
+	// DropOldest, the oldest entry it evicted to make room.
+	DroppedEntries int64
+
+	// BytesInFlight is the number of bytes currently being sent (or
+	// retried) to the logging service.
+	BytesInFlight int64
+
+	// RetriesInFlight is the number of WriteLogEntries calls currently
+	// backed off before a retry attempt.
+	RetriesInFlight int64
+}
+
+// Stats returns a snapshot of l's counters.
+func (l *Logger) Stats() Stats {
+	return Stats{
+		DroppedEntries:  atomic.LoadInt64(&l.stats.droppedEntries),
+		BytesInFlight:   atomic.LoadInt64(&l.stats.bytesInFlight),
+		RetriesInFlight: atomic.LoadInt64(&l.stats.retriesInFlight),
+	}
+}
+
+// queuedEntry is one entry waiting in overflowQueue.
+type queuedEntry struct {
+	ent  *logpb.LogEntry
+	size int
+}
+
+// overflowQueue is the Logger's own bounded, byte-limited FIFO, sized to
+// BufferedByteLimit. The stock bundler only exposes an all-or-nothing
+// BufferedByteLimit with no way to reach in and evict a specific entry, so
+// DropOldest is implemented on top of it with this queue rather than inside
+// it: entries that can't be admitted to the bundler wait here, and if this
+// queue is itself full, the entry at its head is evicted to make room. A
+// background goroutine drains queued entries into the bundler as room frees
+// up, so Log never makes a network call itself.
+type overflowQueue struct {
+	mu    sync.Mutex
+	items []queuedEntry
+	bytes int
+
+	// draining is CAS-guarded so that addToBundler starts at most one
+	// background drain goroutine at a time, instead of piling one up per
+	// overflowing Log call.
+	draining int32
+}
+
+// pushEvictingOldest appends e to q, evicting entries from the head (and
+// reporting each eviction to onEvict) until e fits within limit bytes.
+func (q *overflowQueue) pushEvictingOldest(e queuedEntry, limit int, onEvict func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for q.bytes+e.size > limit && len(q.items) > 0 {
+		q.bytes -= q.items[0].size
+		q.items = q.items[1:]
+		onEvict()
+	}
+	q.items = append(q.items, e)
+	q.bytes += e.size
+}
+
+// popHead atomically removes and returns the head of q, if any. Removing
+// (rather than merely peeking) under the lock is what makes drain safe to
+// call concurrently: once popHead hands an entry out, no other call can see
+// it, so two concurrent drains can never hand the same entry to add twice.
+func (q *overflowQueue) popHead() (queuedEntry, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		return queuedEntry{}, false
+	}
+	head := q.items[0]
+	q.items = q.items[1:]
+	q.bytes -= head.size
+	return head, true
+}
+
+// pushFront restores e to the head of q, e.g. after add has rejected it.
+func (q *overflowQueue) pushFront(e queuedEntry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.items = append([]queuedEntry{e}, q.items...)
+	q.bytes += e.size
+}
+
+// drain repeatedly pops q's head and hands it to add, stopping as soon as
+// the queue empties. If an add fails (the bundler is still full), the entry
+// goes back to the head via pushFront; if retryOnFull is true, drain keeps
+// retrying it instead of giving up, which is what lets Flush guarantee the
+// queue is empty before it returns.
+func (q *overflowQueue) drain(add func(interface{}, int) error, retryOnFull bool) {
+	for {
+		head, ok := q.popHead()
+		if !ok {
+			return
+		}
+		if err := add(head.ent, head.size); err != nil {
+			q.pushFront(head)
+			if retryOnFull {
+				time.Sleep(blockPollInterval)
+				continue
+			}
+			return
+		}
+	}
+}
+
+// startBackgroundDrain runs drain in its own goroutine unless one is
+// already running, so that a burst of overflowing Log calls shares a single
+// drain loop instead of piling up a goroutine each.
+func (q *overflowQueue) startBackgroundDrain(add func(interface{}, int) error) {
+	if !atomic.CompareAndSwapInt32(&q.draining, 0, 1) {
+		return
+	}
+	go func() {
+		defer atomic.StoreInt32(&q.draining, 0)
+		q.drain(add, false)
+	}()
+}
+
+// addToBundler adds ent to l's bundler, applying l.overflow when the
+// bundler reports that it is full (bundler.ErrOverflow).
+func (l *Logger) addToBundler(ctx context.Context, ent *logpb.LogEntry, size int) error {
+	err := l.bundler.Add(ent, size)
+	if err == nil {
+		return nil
+	}
+	if err != bundler.ErrOverflow {
+		return err
+	}
+	switch l.overflow {
+	case DropOldest:
+		l.overflowQueue.pushEvictingOldest(queuedEntry{ent, size}, l.bundler.BufferedByteLimit,
+			func() { atomic.AddInt64(&l.stats.droppedEntries, 1) })
+		l.overflowQueue.startBackgroundDrain(l.bundler.Add)
+		return nil
+
+	case Block:
+		ticker := time.NewTicker(blockPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+				if err := l.bundler.Add(ent, size); err == nil {
+					return nil
+				}
+			}
+		}
+
+	default: // DropNewest
+		atomic.AddInt64(&l.stats.droppedEntries, 1)
+		return bundler.ErrOverflow
+	}
+}
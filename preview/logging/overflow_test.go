@@ -0,0 +1,192 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	ltesting "cloud.google.com/go/preview/logging/internal/testing"
+	"golang.org/x/net/context"
+	"google.golang.org/api/option"
+	logpb "google.golang.org/genproto/googleapis/logging/v2"
+	"google.golang.org/grpc"
+)
+
+// newOverflowTestLogger returns a Logger backed by the ltesting fake, with a
+// buffer small enough that a handful of Log calls will overflow it.
+func newOverflowTestLogger(t *testing.T, opts ...LoggerOption) *Logger {
+	t.Helper()
+	addr, err := ltesting.NewServer()
+	if err != nil {
+		t.Fatalf("creating fake server: %v", err)
+	}
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("dialing %q: %v", addr, err)
+	}
+	c, err := NewClient(context.Background(), "PROJECT_ID", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("creating client: %v", err)
+	}
+	c.OnError = func(error) {}
+	opts = append([]LoggerOption{
+		BufferedByteLimit(64),
+		DelayThreshold(time.Hour), // only a manual Flush should drain the buffer
+	}, opts...)
+	return c.Logger("overflow-test", opts...)
+}
+
+func fillBuffer(t *testing.T, lg *Logger) {
+	t.Helper()
+	// Under Block, a done ctx makes the calls that find the buffer full
+	// return at once instead of waiting for room that never frees up.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 50; i++ {
+		lg.Log(ctx, Entry{Payload: "filling the buffer past its limit"})
+	}
+}
+
+func TestOverflowDropNewest(t *testing.T) {
+	lg := newOverflowTestLogger(t, BufferOverflowPolicy(DropNewest))
+	fillBuffer(t, lg)
+	if got := lg.Stats().DroppedEntries; got == 0 {
+		t.Error("got 0 DroppedEntries, want > 0 under DropNewest")
+	}
+	if err := lg.Flush(); err != nil {
+		t.Errorf("Flush: %v", err)
+	}
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	lg := newOverflowTestLogger(t, BufferOverflowPolicy(DropOldest))
+
+	start := time.Now()
+	fillBuffer(t, lg)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Log calls under DropOldest took %v; want them to never make a network round-trip", elapsed)
+	}
+
+	// The staging queue behind DropOldest is itself bounded by
+	// BufferedByteLimit, so filling well past it must evict (and count)
+	// some of the oldest entries rather than send everything.
+	if got := lg.Stats().DroppedEntries; got == 0 {
+		t.Error("got 0 DroppedEntries, want > 0 once the DropOldest staging queue itself fills up")
+	}
+
+	// Flush must still drain whatever is left, including anything still
+	// queued behind the bundler.
+	if err := lg.Flush(); err != nil {
+		t.Errorf("Flush: %v", err)
+	}
+}
+
+// TestOverflowDropOldestConcurrentNoDuplicateSends exercises many goroutines
+// overflowing a DropOldest Logger concurrently. Each entry must be sent at
+// most once: the staging queue behind DropOldest used to hand the same head
+// entry to two concurrent drains (one peeking the head, then adding it,
+// then removing it, racing with another drain doing the same), so a
+// duplicate here would mean that race is back.
+func TestOverflowDropOldestConcurrentNoDuplicateSends(t *testing.T) {
+	addr, err := ltesting.NewServer()
+	if err != nil {
+		t.Fatalf("creating fake server: %v", err)
+	}
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("dialing %q: %v", addr, err)
+	}
+	c, err := NewClient(context.Background(), "PROJECT_ID", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("creating client: %v", err)
+	}
+	c.OnError = func(error) {}
+	lg := c.Logger("overflow-race-test",
+		BufferOverflowPolicy(DropOldest),
+		BufferedByteLimit(64),
+		DelayThreshold(time.Hour))
+
+	var mu sync.Mutex
+	sent := map[string]int{}
+	ltesting.SetWriteLogEntriesHook(addr, func(req *logpb.WriteLogEntriesRequest) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, e := range req.Entries {
+			if tp, ok := e.Payload.(*logpb.LogEntry_TextPayload); ok {
+				sent[tp.TextPayload]++
+			}
+		}
+		return nil
+	})
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for g := 0; g < 20; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				lg.Log(ctx, Entry{Payload: fmt.Sprintf("g%d-%d", g, i)})
+			}
+		}(g)
+	}
+	wg.Wait()
+	if err := lg.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	for payload, n := range sent {
+		if n > 1 {
+			t.Errorf("entry %q sent %d times, want at most once", payload, n)
+		}
+	}
+}
+
+func TestOverflowBlockRespectsDeadline(t *testing.T) {
+	lg := newOverflowTestLogger(t, BufferOverflowPolicy(Block))
+	fillBuffer(t, lg)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := lg.Log(ctx, Entry{Payload: "one more, should block then time out"})
+	if err != context.DeadlineExceeded {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestOverflowBlockUnblocksOnFlush(t *testing.T) {
+	lg := newOverflowTestLogger(t, BufferOverflowPolicy(Block))
+	fillBuffer(t, lg)
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		done <- lg.Log(ctx, Entry{Payload: "waits for room"})
+	}()
+	// Draining the buffer should free up room for the blocked Log call.
+	if err := lg.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("blocked Log: got %v, want nil once room freed up", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("blocked Log never returned after Flush freed up room")
+	}
+}
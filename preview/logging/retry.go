@@ -0,0 +1,154 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+	logpb "google.golang.org/genproto/googleapis/logging/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultMaxRetries is the default number of retries (beyond the initial
+	// attempt) for a WriteLogEntries call.
+	DefaultMaxRetries = 3
+
+	// DefaultInitialBackoff is the default backoff before the first retry.
+	DefaultInitialBackoff = 100 * time.Millisecond
+
+	// DefaultMaxBackoff caps the exponential backoff between retries.
+	DefaultMaxBackoff = 10 * time.Second
+)
+
+// RetryPredicate reports whether an error returned from WriteLogEntries is
+// worth retrying.
+type RetryPredicate func(err error) bool
+
+// defaultRetryPredicate retries the gRPC codes that are typically transient:
+// Unavailable and ResourceExhausted indicate the service is overloaded,
+// Internal can be a momentary backend hiccup, and DeadlineExceeded here
+// means a single attempt's deadline expired, not the caller's context
+// (that case is handled separately, see writeLogEntriesWithRetry).
+func defaultRetryPredicate(err error) bool {
+	s, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	switch s.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryConfig holds the retry settings of a Logger.
+type retryConfig struct {
+	predicate      RetryPredicate
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+func defaultRetryConfig() retryConfig {
+	return retryConfig{
+		predicate:      defaultRetryPredicate,
+		maxRetries:     DefaultMaxRetries,
+		initialBackoff: DefaultInitialBackoff,
+		maxBackoff:     DefaultMaxBackoff,
+	}
+}
+
+// RetryPolicy sets the function used to decide whether a failed
+// WriteLogEntries call should be retried. The default retries Unavailable,
+// DeadlineExceeded, ResourceExhausted, and Internal.
+func RetryPolicy(p RetryPredicate) LoggerOption {
+	return logOption(func(l *Logger) { l.retry.predicate = p })
+}
+
+// MaxRetries sets the maximum number of retries (beyond the initial
+// attempt) for a single WriteLogEntries call.
+func MaxRetries(n int) LoggerOption {
+	return logOption(func(l *Logger) { l.retry.maxRetries = n })
+}
+
+// InitialBackoff sets the backoff duration before the first retry. Later
+// retries double it, up to MaxBackoff.
+func InitialBackoff(d time.Duration) LoggerOption {
+	return logOption(func(l *Logger) { l.retry.initialBackoff = d })
+}
+
+// MaxBackoff caps the exponential backoff applied between retries.
+func MaxBackoff(d time.Duration) LoggerOption {
+	return logOption(func(l *Logger) { l.retry.maxBackoff = d })
+}
+
+// writeLogEntriesWithRetry calls WriteLogEntries, retrying transient errors
+// with exponential backoff and full jitter. The retry budget and backoff
+// bounds come from l.retry.
+//
+// If ctx is canceled or its deadline expires, that error is returned
+// unwrapped (via ctx.Err(), never a retry.Unwrap or status-wrapped
+// equivalent) so callers can compare it with == or errors.Is against
+// context.Canceled / context.DeadlineExceeded. This takes priority over the
+// retry predicate: a per-attempt DeadlineExceeded from the RPC itself (the
+// caller's ctx is still live) is still eligible for the normal retry path.
+//
+// Retries never re-enter the bundler, so they don't re-add to
+// BufferedByteLimit accounting: the bytes for this batch were already
+// subtracted from the buffer when the bundler handed it to us, and nothing
+// here hands them back until the call, including retries, returns.
+func (l *Logger) writeLogEntriesWithRetry(ctx context.Context, req *logpb.WriteLogEntriesRequest) error {
+	backoff := l.retry.initialBackoff
+	for attempt := 0; ; attempt++ {
+		_, err := l.client.client.WriteLogEntries(ctx, req)
+		if err == nil {
+			return nil
+		}
+		if cerr := ctx.Err(); cerr != nil {
+			return cerr
+		}
+		if attempt >= l.retry.maxRetries || l.retry.predicate == nil || !l.retry.predicate(err) {
+			return err
+		}
+		atomic.AddInt64(&l.stats.retriesInFlight, 1)
+		select {
+		case <-time.After(fullJitter(backoff)):
+		case <-ctx.Done():
+			atomic.AddInt64(&l.stats.retriesInFlight, -1)
+			return ctx.Err()
+		}
+		atomic.AddInt64(&l.stats.retriesInFlight, -1)
+		backoff *= 2
+		if backoff > l.retry.maxBackoff {
+			backoff = l.retry.maxBackoff
+		}
+	}
+}
+
+// fullJitter returns a random duration in [0, d), the "full jitter" strategy
+// recommended for backing off retried RPCs: it spreads retries out evenly
+// instead of letting them cluster at the edge of each backoff step.
+func fullJitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
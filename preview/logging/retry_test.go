@@ -0,0 +1,140 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ltesting "cloud.google.com/go/preview/logging/internal/testing"
+	"golang.org/x/net/context"
+	"google.golang.org/api/option"
+	logpb "google.golang.org/genproto/googleapis/logging/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newRetryTestLogger returns a Logger backed by the ltesting fake, whose
+// WriteLogEntries fails the first len(failures) calls with the given codes,
+// in order, then succeeds; it also returns a counter of the calls made.
+func newRetryTestLogger(t *testing.T, failures []codes.Code) (*Logger, *int32) {
+	t.Helper()
+	addr, err := ltesting.NewServer()
+	if err != nil {
+		t.Fatalf("creating fake server: %v", err)
+	}
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("dialing %q: %v", addr, err)
+	}
+	c, err := NewClient(context.Background(), "PROJECT_ID", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("creating client: %v", err)
+	}
+	var attempts int32
+	c.OnError = func(error) {}
+	lg := c.Logger("retry-test",
+		InitialBackoff(time.Millisecond),
+		MaxBackoff(2*time.Millisecond),
+		MaxRetries(len(failures)+1))
+	ltesting.SetWriteLogEntriesHook(addr, func(*logpb.WriteLogEntriesRequest) error {
+		n := atomic.AddInt32(&attempts, 1)
+		if int(n) <= len(failures) {
+			return status.Error(failures[n-1], "injected transient failure")
+		}
+		return nil
+	})
+	return lg, &attempts
+}
+
+func TestLogSyncRetriesTransientErrors(t *testing.T) {
+	lg, attempts := newRetryTestLogger(t, []codes.Code{codes.Unavailable, codes.ResourceExhausted})
+	if err := lg.LogSync(context.Background(), Entry{Payload: "hi"}); err != nil {
+		t.Fatalf("LogSync: got %v, want nil after retries", err)
+	}
+	if got, want := atomic.LoadInt32(attempts), int32(3); got != want {
+		t.Errorf("attempts: got %d, want %d", got, want)
+	}
+}
+
+func TestLogSyncGivesUpAfterMaxRetries(t *testing.T) {
+	lg, attempts := newRetryTestLogger(t, []codes.Code{
+		codes.Unavailable, codes.Unavailable, codes.Unavailable, codes.Unavailable,
+	})
+	lg.retry.maxRetries = 2 // fewer retries than injected failures
+	err := lg.LogSync(context.Background(), Entry{Payload: "hi"})
+	if err == nil {
+		t.Fatal("LogSync: got nil error, want one after exhausting retries")
+	}
+	if got, want := atomic.LoadInt32(attempts), int32(3); got != want { // 1 initial + 2 retries
+		t.Errorf("attempts: got %d, want %d", got, want)
+	}
+}
+
+func TestLogSyncRetriesPerAttemptDeadlineExceeded(t *testing.T) {
+	// A DeadlineExceeded from the fake here simulates a single RPC attempt
+	// timing out, which is retryable; it must not be confused with the
+	// caller's own ctx.Err(), which is still nil since ctx has no deadline.
+	lg, attempts := newRetryTestLogger(t, []codes.Code{codes.DeadlineExceeded, codes.DeadlineExceeded})
+	if err := lg.LogSync(context.Background(), Entry{Payload: "hi"}); err != nil {
+		t.Fatalf("LogSync: got %v, want nil after retries", err)
+	}
+	if got, want := atomic.LoadInt32(attempts), int32(3); got != want {
+		t.Errorf("attempts: got %d, want %d", got, want)
+	}
+}
+
+// TestStandardLoggerRetriesThroughLog pins severityWriter.Write's call into
+// Log(ctx, e) error down to the retry path it ultimately feeds: a change
+// to Log's signature that left severityWriter.Write on the old call form
+// would fail to compile, but a change that merely got the call site wrong
+// in a way the compiler accepts (e.g. dropping the context) would not be
+// caught by that alone.
+func TestStandardLoggerRetriesThroughLog(t *testing.T) {
+	lg, attempts := newRetryTestLogger(t, []codes.Code{codes.Unavailable})
+	lg.StandardLogger(Info).Print("hi")
+	if err := lg.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if got, want := atomic.LoadInt32(attempts), int32(2); got != want {
+		t.Errorf("attempts: got %d, want %d", got, want)
+	}
+}
+
+func TestLogSyncPropagatesCallerCancellation(t *testing.T) {
+	lg, _ := newRetryTestLogger(t, []codes.Code{codes.Unavailable})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := lg.LogSync(ctx, Entry{Payload: "hi"})
+	if err != context.Canceled {
+		t.Errorf("got %v, want context.Canceled (unwrapped)", err)
+	}
+}
+
+func TestLogRetriesBeforeCallingOnError(t *testing.T) {
+	lg, attempts := newRetryTestLogger(t, []codes.Code{codes.Internal})
+	var gotErr error
+	lg.client.OnError = func(err error) { gotErr = err }
+	lg.Log(context.Background(), Entry{Payload: "hi"})
+	lg.Flush()
+	if gotErr != nil {
+		t.Errorf("OnError fired with %v; want it to not fire since the retry succeeded", gotErr)
+	}
+	if got, want := atomic.LoadInt32(attempts), int32(2); got != want {
+		t.Errorf("attempts: got %d, want %d", got, want)
+	}
+}